@@ -1,10 +1,10 @@
 package oauth
 
 import (
-	"crypto/hmac"
-	"encoding/base64"
+	"bytes"
 	"fmt"
 	"http"
+	"io"
 	"io/ioutil"
 	"os"
 	"rand"
@@ -14,8 +14,7 @@ import (
 )
 
 const (
-	OAUTH_VERSION    = "1.0"
-	SIGNATURE_METHOD = "HMAC-SHA1"
+	OAUTH_VERSION = "1.0"
 
 	CALLBACK_PARAM         = "oauth_callback"
 	CONSUMER_KEY_PARAM     = "oauth_consumer_key"
@@ -44,8 +43,32 @@ type Consumer struct {
 	HttpClient     HttpClient
 	Clock          Clock
 	NonceGenerator NonceGenerator
+
+	// Signer computes oauth_signature. Defaults to HMACSHA1Signer.
+	Signer Signer
+
+	// Logger receives diagnostics (requests made, bodies read).
+	// Defaults to a no-op logger. Secrets are redacted before being
+	// passed to it, but callers embedding this library should still
+	// treat it as a debug-only stream, not something to leave on in
+	// production by default.
+	Logger Logger
+}
+
+// Logger is the diagnostic sink for a Consumer. Debugf is used for
+// routine request/response tracing; Warnf for things worth a closer
+// look but that aren't themselves errors returned to the caller.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
 }
 
+// NopLogger discards everything. It's the default Logger.
+type NopLogger struct{}
+
+func (*NopLogger) Debugf(format string, args ...interface{}) {}
+func (*NopLogger) Warnf(format string, args ...interface{})  {}
+
 type UnauthorizedToken struct {
 	Token       string
 	TokenSecret string
@@ -94,7 +117,9 @@ func (c *Consumer) GetRequestToken() (*UnauthorizedToken, os.Error) {
 	params.Add(CALLBACK_PARAM, c.CallbackUrl)
 
 	req := newGetRequest(c.RequestTokenUrl, params)
-	c.signRequest(req, c.makeKey("")) // We don't have a token secret for the key yet
+	if _, err := c.signRequest(req, "" /* We don't have a token secret yet */); err != nil {
+		return nil, err
+	}
 
 	resp, err := c.getBody(c.RequestTokenUrl, params)
 	if err != nil {
@@ -112,10 +137,15 @@ func (c *Consumer) GetRequestToken() (*UnauthorizedToken, os.Error) {
 		nil
 }
 
-func (c *Consumer) signRequest(req *request, key string) *request {
+func (c *Consumer) signRequest(req *request, tokenSecret string) (*request, os.Error) {
 	base_string := c.requestString(req.method, req.url, req.oauthParams)
-	req.oauthParams.Add(SIGNATURE_PARAM, sign(base_string, key))
-	return req
+	c.Logger.Debugf("signing: %s", base_string)
+	signature, err := c.Signer.Sign(base_string, c.ConsumerSecret, tokenSecret)
+	if err != nil {
+		return nil, err
+	}
+	req.oauthParams.Add(SIGNATURE_PARAM, signature)
+	return req, nil
 }
 
 func (c *Consumer) TokenAuthorizationUrl(token *UnauthorizedToken) string {
@@ -129,7 +159,9 @@ func (c *Consumer) AuthorizeToken(unauthToken *UnauthorizedToken, verificationCo
 	params.Add(TOKEN_PARAM, unauthToken.Token)
 
 	req := newGetRequest(c.AccessTokenUrl, params)
-	c.signRequest(req, c.makeKey(unauthToken.TokenSecret))
+	if _, err := c.signRequest(req, unauthToken.TokenSecret); err != nil {
+		return nil, err
+	}
 
 	resp, err := c.getBody(c.AccessTokenUrl, params)
 
@@ -145,6 +177,60 @@ func (c *Consumer) AuthorizeToken(unauthToken *UnauthorizedToken, verificationCo
 }
 
 func (c *Consumer) Get(url string, userParams map[string]string, token *AuthorizedToken) (*http.Response, os.Error) {
+	req, err := c.Sign("GET", url, userParams, token)
+	if err != nil {
+		return nil, err
+	}
+	return c.HttpClient.Do(req)
+}
+
+// FORM_CONTENT_TYPE is the content type Do recognizes as a form-encoded
+// body whose parameters must be folded into the signature base string.
+const FORM_CONTENT_TYPE = "application/x-www-form-urlencoded"
+
+func (c *Consumer) Post(url string, userParams map[string]string, token *AuthorizedToken) (*http.Response, os.Error) {
+	return c.Do("POST", url, userParams, nil, "", token)
+}
+
+// PostForm POSTs formParams as an application/x-www-form-urlencoded
+// body, signing the request with those parameters folded into the
+// base string as required by RFC 5849 3.4.1.3.1.
+func (c *Consumer) PostForm(url string, formParams map[string]string, token *AuthorizedToken) (*http.Response, os.Error) {
+	return c.Do("POST", url, nil, bytes.NewBufferString(encodeForm(formParams)), FORM_CONTENT_TYPE, token)
+}
+
+func (c *Consumer) Put(url string, userParams map[string]string, token *AuthorizedToken) (*http.Response, os.Error) {
+	return c.Do("PUT", url, userParams, nil, "", token)
+}
+
+func (c *Consumer) Delete(url string, userParams map[string]string, token *AuthorizedToken) (*http.Response, os.Error) {
+	return c.Do("DELETE", url, userParams, nil, "", token)
+}
+
+// Sign builds and signs a request without executing it, so callers can
+// inspect or modify it (add headers, swap transport, use context
+// cancellation) before dispatch. Get, Post, Put and Delete are thin
+// wrappers around Sign followed by HttpClient.Do.
+func (c *Consumer) Sign(method, url string, userParams map[string]string, token *AuthorizedToken) (*http.Request, os.Error) {
+	return c.signRequestParams(method, url, userParams, nil, "", token)
+}
+
+// Do signs and executes an arbitrary request. userParams are folded
+// into the query string (as with Get); if body is an
+// application/x-www-form-urlencoded payload its parameters are parsed
+// and folded into the signature base string too, per RFC 5849
+// 3.4.1.3.1. The Authorization header carries only the oauth_*
+// parameters and c.AdditionalParams; userParams and the form body are
+// never added to it.
+func (c *Consumer) Do(method, url string, userParams map[string]string, body io.Reader, contentType string, token *AuthorizedToken) (*http.Response, os.Error) {
+	req, err := c.signRequestParams(method, url, userParams, body, contentType, token)
+	if err != nil {
+		return nil, err
+	}
+	return c.HttpClient.Do(req)
+}
+
+func (c *Consumer) signRequestParams(method, url string, userParams map[string]string, body io.Reader, contentType string, token *AuthorizedToken) (*http.Request, os.Error) {
 	allParams := c.baseParams(c.ConsumerKey, c.AdditionalParams)
 	authParams := allParams.Clone()
 
@@ -158,19 +244,92 @@ func (c *Consumer) Get(url string, userParams map[string]string, token *Authoriz
 		}
 	}
 
+	var bodyBytes []byte
+	if body != nil {
+		var err os.Error
+		bodyBytes, err = ioutil.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if contentType == FORM_CONTENT_TYPE && len(bodyBytes) > 0 {
+		bodyParams, err := http.ParseQuery(string(bodyBytes))
+		if err != nil {
+			return nil, err
+		}
+		for key, values := range bodyParams {
+			for _, value := range values {
+				allParams.Add(key, value)
+			}
+		}
+	}
+
 	allParams.Add(TOKEN_PARAM, token.Token)
 	authParams.Add(TOKEN_PARAM, token.Token)
 
-	key := c.makeKey(token.TokenSecret)
+	base_string := c.requestString(method, url, allParams)
+	c.Logger.Debugf("signing: %s", base_string)
+	signature, err := c.Signer.Sign(base_string, c.ConsumerSecret, token.TokenSecret)
+	if err != nil {
+		return nil, err
+	}
+	authParams.Add(SIGNATURE_PARAM, signature)
+
+	var reqBody io.Reader
+	if bodyBytes != nil {
+		reqBody = bytes.NewBuffer(bodyBytes)
+	}
+
+	return c.newRequest(method, url+queryParams, authParams, reqBody, contentType)
+}
+
+// TwoLeggedRequest builds and signs a two-legged (0-legged) request:
+// no oauth_token parameter, signing key is ConsumerSecret& with no
+// token secret. Returned unexecuted; see MakeAuthorizedRequest.
+func (c *Consumer) TwoLeggedRequest(method, url string, userParams map[string]string) (*http.Request, os.Error) {
+	allParams := c.baseParams(c.ConsumerKey, c.AdditionalParams)
+	authParams := allParams.Clone()
 
-	base_string := c.requestString("GET", url, allParams)
-	authParams.Add(SIGNATURE_PARAM, sign(base_string, key))
+	queryParams := ""
+	separator := "?"
+	if userParams != nil {
+		for key, value := range userParams {
+			allParams.Add(key, value)
+			queryParams += separator + escape(key) + "=" + escape(value)
+			separator = "&"
+		}
+	}
 
-	return c.get(url+queryParams, authParams)
+	base_string := c.requestString(method, url, allParams)
+	c.Logger.Debugf("signing: %s", base_string)
+	signature, err := c.Signer.Sign(base_string, c.ConsumerSecret, "")
+	if err != nil {
+		return nil, err
+	}
+	authParams.Add(SIGNATURE_PARAM, signature)
+
+	return c.newRequest(method, url+queryParams, authParams, nil, "")
+}
+
+// MakeAuthorizedRequest signs and executes a two-legged OAuth request.
+// See TwoLeggedRequest for details on how the request is signed.
+func (c *Consumer) MakeAuthorizedRequest(method, url string, userParams map[string]string) (*http.Response, os.Error) {
+	req, err := c.TwoLeggedRequest(method, url, userParams)
+	if err != nil {
+		return nil, err
+	}
+	return c.HttpClient.Do(req)
 }
 
-func (c *Consumer) makeKey(tokenSecret string) string {
-	return escape(c.ConsumerSecret) + "&" + escape(tokenSecret)
+func encodeForm(params map[string]string) string {
+	encoded := ""
+	separator := ""
+	for key, value := range params {
+		encoded += separator + escape(key) + "=" + escape(value)
+		separator = "&"
+	}
+	return encoded
 }
 
 func parseTokenAndSecret(data string) (*string, *string, os.Error) {
@@ -199,13 +358,19 @@ func (c *Consumer) init() {
 	if c.NonceGenerator == nil {
 		c.NonceGenerator = rand.New(rand.NewSource(c.Clock.Seconds()))
 	}
+	if c.Signer == nil {
+		c.Signer = &HMACSHA1Signer{}
+	}
+	if c.Logger == nil {
+		c.Logger = &NopLogger{}
+	}
 }
 
 func (c *Consumer) baseParams(consumerKey string, additionalParams map[string]string) *OrderedParams {
 	c.init()
 	params := NewOrderedParams()
 	params.Add(VERSION_PARAM, OAUTH_VERSION)
-	params.Add(SIGNATURE_METHOD_PARAM, SIGNATURE_METHOD)
+	params.Add(SIGNATURE_METHOD_PARAM, c.Signer.Name())
 	params.Add(TIMESTAMP_PARAM, strconv.Itoa64(c.Clock.Seconds()))
 	params.Add(NONCE_PARAM, strconv.Itoa64(c.NonceGenerator.Int63()))
 	params.Add(CONSUMER_KEY_PARAM, consumerKey)
@@ -215,30 +380,20 @@ func (c *Consumer) baseParams(consumerKey string, additionalParams map[string]st
 	return params
 }
 
-func sign(message string, key string) string {
-	fmt.Println("Signing:" + message)
-	fmt.Println("Key:" + key)
-	hashfun := hmac.NewSHA1([]byte(key))
-	hashfun.Write([]byte(message))
-	rawsignature := hashfun.Sum()
-	base64signature := make([]byte, base64.StdEncoding.EncodedLen(len(rawsignature)))
-	base64.StdEncoding.Encode(base64signature, rawsignature)
-	return string(base64signature)
-}
-
 func escape(input string) string {
 	return http.URLEscape(input)
 }
 
 func (c *Consumer) requestString(method string, url string, params *OrderedParams) string {
 	result := method + "&" + escape(url)
-	for pos, key := range params.Keys() {
+	keys, values := params.Keys(), params.Values()
+	for pos, key := range keys {
 		if pos == 0 {
 			result += "&"
 		} else {
 			result += escape("&")
 		}
-		result += escape(fmt.Sprintf("%s=%s", key, params.Get(key)))
+		result += escape(fmt.Sprintf("%s=%s", key, values[pos]))
 	}
 	return result
 }
@@ -248,23 +403,48 @@ func (c *Consumer) getBody(url string, oauthParams *OrderedParams) (*string, os.
 	if err != nil {
 		return nil, err
 	}
-	fmt.Println("About to readbody")
-	bytes, err := ioutil.ReadAll(resp.Body)
-	fmt.Println("Done readbody")
+	bodyBytes, err := ioutil.ReadAll(resp.Body)
 	resp.Body.Close()
 	if err != nil {
 		return nil, err
 	}
-	str := string(bytes)
-	fmt.Println("BODY RESPONSE: " + str)
+	str := string(bodyBytes)
+	c.Logger.Debugf("body response: %s", redactSecrets(str))
 	return &str, nil
 }
 
+// redactSecrets is used before logging a response body. Request/access
+// token responses carry oauth_token_secret in the clear, so if one's
+// present the whole body is withheld rather than risk leaking it into
+// logs.
+func redactSecrets(body string) string {
+	parts, err := http.ParseQuery(body)
+	if err == nil && len(parts[TOKEN_SECRET_PARAM]) > 0 {
+		return "[redacted: contains " + TOKEN_SECRET_PARAM + "]"
+	}
+	return body
+}
+
 func (c *Consumer) get(url string, oauthParams *OrderedParams) (*http.Response, os.Error) {
-	fmt.Println("GET url: " + url)
+	return c.do("GET", url, oauthParams, nil, "")
+}
+
+func (c *Consumer) do(method, url string, oauthParams *OrderedParams, body io.Reader, contentType string) (*http.Response, os.Error) {
+	req, err := c.newRequest(method, url, oauthParams, body, contentType)
+	if err != nil {
+		return nil, err
+	}
+	return c.HttpClient.Do(req)
+}
+
+// newRequest builds the *http.Request for a already-signed call,
+// setting the Authorization header from oauthParams. It never touches
+// the network; callers execute it themselves via HttpClient.
+func (c *Consumer) newRequest(method, url string, oauthParams *OrderedParams, body io.Reader, contentType string) (*http.Request, os.Error) {
+	c.Logger.Debugf("%s url: %s", method, url)
 
 	var req http.Request
-	req.Method = "GET"
+	req.Method = method
 	req.Header = http.Header{}
 	parsedurl, err := http.ParseURL(url)
 	if err != nil {
@@ -272,6 +452,17 @@ func (c *Consumer) get(url string, oauthParams *OrderedParams) (*http.Response,
 	}
 	req.URL = parsedurl
 
+	if body != nil {
+		if rc, ok := body.(io.ReadCloser); ok {
+			req.Body = rc
+		} else {
+			req.Body = ioutil.NopCloser(body)
+		}
+	}
+	if contentType != "" {
+		req.Header.Add("Content-Type", contentType)
+	}
+
 	authhdr := "OAuth "
 	for pos, key := range oauthParams.Keys() {
 		if pos > 0 {
@@ -279,10 +470,23 @@ func (c *Consumer) get(url string, oauthParams *OrderedParams) (*http.Response,
 		}
 		authhdr += key + "=\"" + oauthParams.Get(key) + "\""
 	}
-	fmt.Println("AUTH-HDR: " + authhdr)
+	// Under PLAINTEXT the signature is the secrets themselves, so the
+	// logged copy of the header has oauth_signature redacted.
+	loghdr := "OAuth "
+	for pos, key := range oauthParams.Keys() {
+		if pos > 0 {
+			loghdr += ",\n    "
+		}
+		value := oauthParams.Get(key)
+		if key == SIGNATURE_PARAM {
+			value = "[redacted]"
+		}
+		loghdr += key + "=\"" + value + "\""
+	}
+	c.Logger.Debugf("AUTH-HDR: %s", loghdr)
 	req.Header.Add("Authorization", authhdr)
 
-	return c.HttpClient.Do(&req)
+	return &req, nil
 }
 
 //
@@ -290,14 +494,16 @@ func (c *Consumer) get(url string, oauthParams *OrderedParams) (*http.Response,
 //
 
 type OrderedParams struct {
-	allParams   map[string]string
-	keyOrdering []string
+	allParams     map[string]string
+	keyOrdering   []string
+	valueOrdering []string
 }
 
 func NewOrderedParams() *OrderedParams {
 	return &OrderedParams{
-		allParams:   make(map[string]string),
-		keyOrdering: make([]string, 0),
+		allParams:     make(map[string]string),
+		keyOrdering:   make([]string, 0),
+		valueOrdering: make([]string, 0),
 	}
 }
 
@@ -305,11 +511,19 @@ func (o *OrderedParams) Get(key string) string {
 	return o.allParams[key]
 }
 
+// Keys returns the parameter names, sorted per RFC 5849 3.4.1.3.2: by
+// name first, then (for repeated names, e.g. form arrays) by value.
 func (o *OrderedParams) Keys() []string {
 	sort.Sort(o)
 	return o.keyOrdering
 }
 
+// Values returns the parameter values in the same order as Keys.
+func (o *OrderedParams) Values() []string {
+	sort.Sort(o)
+	return o.valueOrdering
+}
+
 func (o *OrderedParams) Add(key, value string) {
 	o.add(key, http.URLEscape(value))
 }
@@ -317,6 +531,7 @@ func (o *OrderedParams) Add(key, value string) {
 func (o *OrderedParams) add(key, value string) {
 	o.allParams[key] = value
 	o.keyOrdering = append(o.keyOrdering, key)
+	o.valueOrdering = append(o.valueOrdering, value)
 }
 
 func (o *OrderedParams) Len() int {
@@ -324,17 +539,22 @@ func (o *OrderedParams) Len() int {
 }
 
 func (o *OrderedParams) Less(i int, j int) bool {
-	return o.keyOrdering[i] < o.keyOrdering[j]
+	if o.keyOrdering[i] != o.keyOrdering[j] {
+		return o.keyOrdering[i] < o.keyOrdering[j]
+	}
+	return o.valueOrdering[i] < o.valueOrdering[j]
 }
 
 func (o *OrderedParams) Swap(i int, j int) {
 	o.keyOrdering[i], o.keyOrdering[j] = o.keyOrdering[j], o.keyOrdering[i]
+	o.valueOrdering[i], o.valueOrdering[j] = o.valueOrdering[j], o.valueOrdering[i]
 }
 
 func (o *OrderedParams) Clone() *OrderedParams {
 	clone := NewOrderedParams()
-	for _, key := range o.Keys() {
-		clone.add(key, o.Get(key))
+	keys, values := o.Keys(), o.Values()
+	for i, key := range keys {
+		clone.add(key, values[i])
 	}
 	return clone
 }
@@ -0,0 +1,217 @@
+package oauth
+
+import (
+	"bytes"
+	"fmt"
+	"http"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+type fixedClock struct{}
+
+func (fixedClock) Seconds() int64 { return 1300000000 }
+
+type fixedNonce struct{}
+
+func (fixedNonce) Int63() int64 { return 42 }
+
+// capturingSigner records the base string and token secret it was
+// asked to sign instead of actually signing, so a test can assert on
+// them directly.
+type capturingSigner struct {
+	base        string
+	tokenSecret string
+}
+
+func (*capturingSigner) Name() string { return "HMAC-SHA1" }
+
+func (s *capturingSigner) Sign(base, consumerSecret, tokenSecret string) (string, os.Error) {
+	s.base = base
+	s.tokenSecret = tokenSecret
+	return "stub-signature", nil
+}
+
+type capturingHttpClient struct {
+	req  *http.Request
+	resp *http.Response
+}
+
+func (c *capturingHttpClient) Do(req *http.Request) (*http.Response, os.Error) {
+	c.req = req
+	if c.resp != nil {
+		return c.resp, nil
+	}
+	return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewBufferString(""))}, nil
+}
+
+// capturingLogger records every Debugf call so a test can assert on
+// what was logged.
+type capturingLogger struct {
+	debugs []string
+}
+
+func (l *capturingLogger) Debugf(format string, args ...interface{}) {
+	l.debugs = append(l.debugs, fmt.Sprintf(format, args...))
+}
+
+func (l *capturingLogger) Warnf(format string, args ...interface{}) {}
+
+// Pins the RFC 5849 3.4.1.3.1 base-string construction for PostForm:
+// form body params must be folded in alongside the oauth_* params, and
+// must not leak into the Authorization header.
+func TestPostFormSigning(t *testing.T) {
+	signer := &capturingSigner{}
+	client := &capturingHttpClient{}
+	c := &Consumer{
+		ConsumerKey:    "key",
+		ConsumerSecret: "cs",
+		Clock:          fixedClock{},
+		NonceGenerator: fixedNonce{},
+		Signer:         signer,
+		HttpClient:     client,
+	}
+	token := &AuthorizedToken{Token: "tok", TokenSecret: "ts"}
+
+	if _, err := c.PostForm("http://example.com/post", map[string]string{"a": "1", "b": "2"}, token); err != nil {
+		t.Fatalf("PostForm: %v", err)
+	}
+
+	want := "POST&http%3A%2F%2Fexample.com%2Fpost&" +
+		"a%3D1%26b%3D2%26oauth_consumer_key%3Dkey%26oauth_nonce%3D42%26" +
+		"oauth_signature_method%3DHMAC-SHA1%26oauth_timestamp%3D1300000000%26" +
+		"oauth_token%3Dtok%26oauth_version%3D1.0"
+	if signer.base != want {
+		t.Errorf("base string = %q, want %q", signer.base, want)
+	}
+
+	authhdr := client.req.Header.Get("Authorization")
+	if strings.Contains(authhdr, "a=1") || strings.Contains(authhdr, "b=2") {
+		t.Errorf("form params leaked into Authorization header: %s", authhdr)
+	}
+}
+
+// Pins the two-legged signing semantics: no oauth_token parameter, and
+// the signer is called with an empty token secret (key is
+// ConsumerSecret& alone).
+func TestTwoLeggedRequestSigning(t *testing.T) {
+	signer := &capturingSigner{}
+	client := &capturingHttpClient{}
+	c := &Consumer{
+		ConsumerKey:    "key",
+		ConsumerSecret: "cs",
+		Clock:          fixedClock{},
+		NonceGenerator: fixedNonce{},
+		Signer:         signer,
+		HttpClient:     client,
+	}
+
+	if _, err := c.TwoLeggedRequest("GET", "http://example.com/get", map[string]string{"a": "1"}); err != nil {
+		t.Fatalf("TwoLeggedRequest: %v", err)
+	}
+
+	if strings.Contains(signer.base, "oauth_token%3D") || strings.Contains(signer.base, "oauth_token=") {
+		t.Errorf("two-legged base string should have no oauth_token param, got %q", signer.base)
+	}
+	if signer.tokenSecret != "" {
+		t.Errorf("two-legged signer called with tokenSecret = %q, want empty", signer.tokenSecret)
+	}
+
+	want := "GET&http%3A%2F%2Fexample.com%2Fget&" +
+		"a%3D1%26oauth_consumer_key%3Dkey%26oauth_nonce%3D42%26" +
+		"oauth_signature_method%3DHMAC-SHA1%26oauth_timestamp%3D1300000000%26" +
+		"oauth_version%3D1.0"
+	if signer.base != want {
+		t.Errorf("base string = %q, want %q", signer.base, want)
+	}
+
+	if _, err := c.MakeAuthorizedRequest("GET", "http://example.com/get", map[string]string{"a": "1"}); err != nil {
+		t.Fatalf("MakeAuthorizedRequest: %v", err)
+	}
+	if client.req == nil {
+		t.Fatalf("MakeAuthorizedRequest never executed a request")
+	}
+}
+
+// Proves Consumer actually routes the signing base string through
+// Logger.Debugf, not just that Logger compiles.
+func TestLoggerReceivesSigningBaseString(t *testing.T) {
+	signer := &capturingSigner{}
+	logger := &capturingLogger{}
+	c := &Consumer{
+		ConsumerKey:    "key",
+		ConsumerSecret: "cs",
+		Clock:          fixedClock{},
+		NonceGenerator: fixedNonce{},
+		Signer:         signer,
+		Logger:         logger,
+		HttpClient:     &capturingHttpClient{},
+	}
+	token := &AuthorizedToken{Token: "tok", TokenSecret: "ts"}
+
+	if _, err := c.Get("http://example.com/get", nil, token); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+
+	found := false
+	for _, msg := range logger.debugs {
+		if strings.Contains(msg, signer.base) {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Logger never saw the signing base string %q; got %v", signer.base, logger.debugs)
+	}
+}
+
+// Proves redactSecrets actually withholds a body containing
+// oauth_token_secret, and leaves an ordinary body untouched.
+func TestRedactSecretsWithholdsTokenSecret(t *testing.T) {
+	withSecret := "oauth_token=abc&oauth_token_secret=supersecret"
+	redacted := redactSecrets(withSecret)
+	if strings.Contains(redacted, "supersecret") {
+		t.Errorf("redactSecrets leaked the token secret: %q", redacted)
+	}
+	if !strings.Contains(redacted, TOKEN_SECRET_PARAM) {
+		t.Errorf("redactSecrets should say which param was withheld, got %q", redacted)
+	}
+
+	plain := "foo=bar"
+	if got := redactSecrets(plain); got != plain {
+		t.Errorf("redactSecrets altered a body with no secret: got %q, want %q", got, plain)
+	}
+}
+
+// Proves getBody never logs a raw response body containing a token
+// secret, end to end through Logger.
+func TestGetBodyRedactsTokenSecretInLog(t *testing.T) {
+	logger := &capturingLogger{}
+	client := &capturingHttpClient{
+		resp: &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(bytes.NewBufferString("oauth_token=abc&oauth_token_secret=supersecret")),
+		},
+	}
+	c := &Consumer{
+		ConsumerKey:    "key",
+		ConsumerSecret: "cs",
+		Clock:          fixedClock{},
+		NonceGenerator: fixedNonce{},
+		Signer:         &capturingSigner{},
+		Logger:         logger,
+		HttpClient:     client,
+	}
+
+	params := c.baseParams(c.ConsumerKey, nil)
+	if _, err := c.getBody("http://example.com/token", params); err != nil {
+		t.Fatalf("getBody: %v", err)
+	}
+
+	for _, msg := range logger.debugs {
+		if strings.Contains(msg, "supersecret") {
+			t.Errorf("getBody logged a raw token secret: %q", msg)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package oauth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+)
+
+// Signer computes oauth_signature. Consumer.Signer defaults to
+// HMACSHA1Signer if left nil.
+type Signer interface {
+	// Name is the oauth_signature_method value.
+	Name() string
+	Sign(base, consumerSecret, tokenSecret string) (string, os.Error)
+}
+
+// HMACSHA1Signer is the default Signer.
+type HMACSHA1Signer struct{}
+
+func (*HMACSHA1Signer) Name() string { return "HMAC-SHA1" }
+
+func (*HMACSHA1Signer) Sign(base, consumerSecret, tokenSecret string) (string, os.Error) {
+	key := escape(consumerSecret) + "&" + escape(tokenSecret)
+	hashfun := hmac.NewSHA1([]byte(key))
+	hashfun.Write([]byte(base))
+	rawsignature := hashfun.Sum()
+	signature := make([]byte, base64.StdEncoding.EncodedLen(len(rawsignature)))
+	base64.StdEncoding.Encode(signature, rawsignature)
+	return string(signature), nil
+}
+
+// RSASHA1Signer signs with an RSA private key; the token secret plays
+// no part in the key.
+type RSASHA1Signer struct {
+	PrivateKey *rsa.PrivateKey
+}
+
+func (*RSASHA1Signer) Name() string { return "RSA-SHA1" }
+
+func (s *RSASHA1Signer) Sign(base, consumerSecret, tokenSecret string) (string, os.Error) {
+	hash := sha1.New()
+	hash.Write([]byte(base))
+	digest := hash.Sum()
+
+	rawsignature, err := rsa.SignPKCS1v15(rand.Reader, s.PrivateKey, crypto.SHA1, digest)
+	if err != nil {
+		return "", os.NewError("oauth: rsa signing failed: " + err.String())
+	}
+
+	signature := make([]byte, base64.StdEncoding.EncodedLen(len(rawsignature)))
+	base64.StdEncoding.Encode(signature, rawsignature)
+	return string(signature), nil
+}
+
+// LoadRSAPrivateKeyPEM reads and parses a PEM-encoded RSA private key.
+func LoadRSAPrivateKeyPEM(path string) (*rsa.PrivateKey, os.Error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, os.NewError("oauth: no PEM data found in " + path)
+	}
+
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, os.NewError("oauth: failed to parse RSA private key: " + err.String())
+	}
+	return key, nil
+}
+
+// PlaintextSigner implements the PLAINTEXT method (RFC 5849 3.4.4):
+// the "signature" is just the key in the clear, so only use it over a
+// secure transport.
+type PlaintextSigner struct{}
+
+func (*PlaintextSigner) Name() string { return "PLAINTEXT" }
+
+func (*PlaintextSigner) Sign(base, consumerSecret, tokenSecret string) (string, os.Error) {
+	return escape(consumerSecret) + "&" + escape(tokenSecret), nil
+}
@@ -0,0 +1,40 @@
+package oauth
+
+import (
+	"testing"
+)
+
+// These vectors pin each Signer to a fixed base string/consumer
+// secret/token secret pair so a subtly wrong implementation (escape
+// order, digest-vs-message signing, padding) fails loudly instead of
+// as a cryptic 401 from a remote API.
+func TestSigners(t *testing.T) {
+	rsaKey, err := LoadRSAPrivateKeyPEM("testdata/rsa_test_key.pem")
+	if err != nil {
+		t.Fatalf("LoadRSAPrivateKeyPEM: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		signer Signer
+		want   string
+	}{
+		{"HMAC-SHA1", &HMACSHA1Signer{}, "gmKuNQWWBVnBvRQjILl9GJbj1Wc="},
+		{"PLAINTEXT", &PlaintextSigner{}, "cs&ts"},
+		{"RSA-SHA1", &RSASHA1Signer{PrivateKey: rsaKey},
+			"VZk4oryczUlkil1SXPiNF4d2tHLDb1q56o8XRkvPmOGBA+6/x8m2x4pW+3CB/apAPStFdT/SFR2OJoM1pBV/7ZSNyIwMebxjO/JzjdrZxO1k8ZZx38b4CXtBlQPlt+PyWWIAf8WIlx8tWrj7CDvieYgLEpob+ZvVe4xGZv7u90U="},
+	}
+
+	for _, test := range tests {
+		if test.signer.Name() != test.name {
+			t.Errorf("%s: Name() = %q, want %q", test.name, test.signer.Name(), test.name)
+		}
+		sig, err := test.signer.Sign("base_string", "cs", "ts")
+		if err != nil {
+			t.Fatalf("%s: Sign: %v", test.name, err)
+		}
+		if sig != test.want {
+			t.Errorf("%s: Sign() = %q, want %q", test.name, sig, test.want)
+		}
+	}
+}